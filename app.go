@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log"
 	"net"
+
+	"github.com/HOTSONHONET/cam-feed/pkg/hls"
+	"github.com/HOTSONHONET/cam-feed/pkg/rtsp"
 )
 
 // App struct
@@ -33,6 +36,16 @@ func (a *App) startup(ctx context.Context) {
 			log.Printf("server closed: %v\n", err)
 		}
 	}()
+	go func() {
+		if err := rtsp.Serve(ctx, ":8554", a.h.Router()); err != nil {
+			log.Printf("rtsp server closed: %v\n", err)
+		}
+	}()
+	go func() {
+		if err := hls.Serve(ctx, ":8080", a.h.Router(), a.h.Auth()); err != nil {
+			log.Printf("hls server closed: %v\n", err)
+		}
+	}()
 }
 
 func getLocalIP() string {
@@ -1,29 +1,34 @@
 package main
 
 import (
+	"context"
 	"log"
-	"net"
 
+	"github.com/HOTSONHONET/cam-feed/internal/hub"
+	"github.com/HOTSONHONET/cam-feed/pkg/hls"
 	"github.com/HOTSONHONET/cam-feed/pkg/rtsp"
 )
 
-// Initializing TCP listener and dispatches each connection
+// Standalone entrypoint that runs the hub's WSS ingest/view server
+// alongside the RTSP and HLS servers, sharing one stream router between
+// all three.
 func main() {
-	ln, err := net.Listen("tcp", ":8554")
-	if err != nil {
-		log.Fatal(err)
-	}
+	h := hub.New()
+	ctx := context.Background()
 
-	defer ln.Close()
-	log.Println("[INFO] RTSP server listening on :8554")
+	go func() {
+		if err := rtsp.Serve(ctx, ":8554", h.Router()); err != nil {
+			log.Printf("[ERROR] rtsp server: %v", err)
+		}
+	}()
 
-	for {
-		conn, err := ln.Accept()
-		if err != nil {
-			log.Println("[ERROR] accept: ", err)
-			continue
+	go func() {
+		if err := hls.Serve(ctx, ":8080", h.Router(), h.Auth()); err != nil {
+			log.Printf("[ERROR] hls server: %v", err)
 		}
+	}()
 
-		go rtsp.HandleConn(conn)
+	if err := h.StartServers(ctx); err != nil {
+		log.Fatal(err)
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -24,15 +25,28 @@ type StreamMeta struct {
 	Height   int    `json:"height"`
 	FPS      int    `json:"fps"`
 	LastSeen int64  `json:"last_seen,omitempty"`
+
+	// Codec metadata needed by consumers that can't just forward the raw
+	// WebSocket payload, e.g. the RTSP server's DESCRIBE/SDP response.
+	Codec string `json:"codec,omitempty"`
+	SPS   []byte `json:"sps,omitempty"`
+	PPS   []byte `json:"pps,omitempty"`
 }
 
 type Hub struct {
 	upgrader websocket.Upgrader
 
 	mu      sync.RWMutex
-	viewers map[string]map[*websocket.Conn]bool // room -> viewers
-	ingest  map[string]*websocket.Conn          // deviceID -> conn
-	metas   map[string]StreamMeta               // deviceID -> meta
+	viewers map[string]map[*websocket.Conn]*viewer // room -> viewers
+	ingest  map[string]*websocket.Conn             // deviceID -> conn
+	metas   map[string]StreamMeta                  // deviceID -> meta
+
+	router   *StreamRouter
+	sources  map[string]context.CancelFunc // deviceID -> stop func, for /sources
+	serveCtx context.Context
+
+	auth        Auth
+	roomConfigs map[string]RoomConfig // room -> connect-time limits
 }
 
 // one write mutex per websocket.Conn to prevent concurrent writes
@@ -74,6 +88,11 @@ func forgetConn(c *websocket.Conn) {
 }
 
 func New() *Hub {
+	secret := []byte(os.Getenv("CAMFEED_TOKEN_SECRET"))
+	if len(secret) == 0 {
+		secret = randomSecret()
+	}
+
 	return &Hub{
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  MaxReadBufferSize,
@@ -84,12 +103,28 @@ func New() *Hub {
 			EnableCompression: false,
 			HandshakeTimeout:  10 * time.Second,
 		},
-		viewers: map[string]map[*websocket.Conn]bool{},
-		ingest:  map[string]*websocket.Conn{},
-		metas:   map[string]StreamMeta{},
+		viewers:     map[string]map[*websocket.Conn]*viewer{},
+		ingest:      map[string]*websocket.Conn{},
+		metas:       map[string]StreamMeta{},
+		router:      NewStreamRouter(),
+		sources:     map[string]context.CancelFunc{},
+		auth:        NewHMACAuth(secret),
+		roomConfigs: map[string]RoomConfig{},
 	}
 }
 
+// Router exposes the hub's stream bus so other subsystems (e.g. the RTSP
+// server) can subscribe to the same frames HandleIngest publishes.
+func (h *Hub) Router() *StreamRouter {
+	return h.router
+}
+
+// Auth exposes the hub's token validator so other subsystems (e.g. the HLS
+// server) can gate access the same way /view does.
+func (h *Hub) Auth() Auth {
+	return h.auth
+}
+
 func (h *Hub) HealthCheck(w http.ResponseWriter, _ *http.Request) {
 	w.Write([]byte("I am alive"))
 }
@@ -97,6 +132,26 @@ func (h *Hub) HealthCheck(w http.ResponseWriter, _ *http.Request) {
 // Endpont to recieved frames from cameras
 // and send to viewers and also notify them
 func (h *Hub) HandleIngest(w http.ResponseWriter, r *http.Request) {
+	room := r.URL.Query().Get("room")
+	if strings.TrimSpace(room) == "" {
+		room = DefaultRoom
+	}
+
+	// Auth is checked before Upgrade so a rejection is a plain 401, not a
+	// hijacked connection we'd have to close ourselves.
+	claims, err := h.auth.Validate(r.URL.Query().Get("token"), room, RolePublish)
+	if err != nil {
+		log.Printf("ingest auth rejected for room %q: %v", room, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	cfg := h.roomConfig(room)
+	if cfg.MaxDevices > 0 && h.deviceCount(room) >= cfg.MaxDevices {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	c, err := h.upgrader.Upgrade(w, r, nil)
 
 	if err != nil {
@@ -118,8 +173,22 @@ func (h *Hub) HandleIngest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if meta.Room == "" {
-		meta.Room = DefaultRoom
+		meta.Room = room
+	} else if meta.Room != room {
+		log.Printf("ingest meta room %q does not match authenticated room %q", meta.Room, room)
+		return
+	}
+
+	if claims.DeviceID != "" && claims.DeviceID != meta.DeviceID {
+		log.Printf("ingest token minted for device %q, got %q", claims.DeviceID, meta.DeviceID)
+		return
+	}
+
+	if !h.RegisterStream(meta) {
+		log.Printf("ingest rejected for device %q in room %q: room full or codec not allowed", meta.DeviceID, room)
+		return
 	}
+	defer h.UnregisterStream(meta)
 
 	// Updating the hub state
 	h.mu.Lock()
@@ -131,16 +200,6 @@ func (h *Hub) HandleIngest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.ingest[meta.DeviceID] = c
-	meta.LastSeen = time.Now().UnixMilli()
-	h.metas[meta.DeviceID] = meta
-
-	// Collecting all viewers of the room
-	var roomViewers []*websocket.Conn
-	if vset := h.viewers[meta.Room]; vset != nil {
-		for v := range vset {
-			roomViewers = append(roomViewers, v)
-		}
-	}
 	h.mu.Unlock()
 
 	// Closing old connection after releasing the lock
@@ -148,16 +207,7 @@ func (h *Hub) HandleIngest(w http.ResponseWriter, r *http.Request) {
 		forgetConn(old)
 	}
 
-	// Notifying all the viewers about the new screen
-	if len(roomViewers) > 0 {
-		event := map[string]any{"type": "join", "stream": meta}
-		for _, viewerConn := range roomViewers {
-			_ = safeWriteJSON(viewerConn, event)
-		}
-	}
-
 	// Reading frames and forwarding to viewers
-	deviceID := meta.DeviceID
 	for {
 
 		c.SetReadLimit(int64(MaxReadBufferSizeForFrames))
@@ -183,56 +233,70 @@ func (h *Hub) HandleIngest(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		// Sending new Frames to viewers
-		header := make([]byte, 2+len(deviceID))
-		binary.BigEndian.PutUint16(header[:2], uint16(len(deviceID)))
-		copy(header[2:], []byte(deviceID))
+		h.publishFrame(meta, frame)
+	}
 
-		payload := append(header, frame...)
+	// Cleaning up the ingest connection; UnregisterStream (deferred above)
+	// handles h.metas and the viewer "leave" notification.
+	h.mu.Lock()
+	delete(h.ingest, meta.DeviceID)
+	h.mu.Unlock()
+}
 
-		// Collecting websocket conn of viewers
-		h.mu.RLock()
-		var viewers []*websocket.Conn
-		for v := range h.viewers[meta.Room] {
-			viewers = append(viewers, v)
-		}
-		h.mu.RUnlock()
+// RegisterStream enforces room limits against meta and, if the room accepts
+// it, marks the device live and notifies existing viewers with a "join"
+// event — exactly what HandleIngest did inline before a device's first
+// frame. RTSPSource and the WHIP handler call this too, so a pulled or
+// WHIP-published stream shows up in HandleManifest/the initial viewer
+// manifest and gets the same room ACL checks HandleIngest applies, instead
+// of only having frames flow through publishFrame with no discovery path.
+// ok is false if the room is full or meta.Codec isn't allowed; the caller
+// must not publish frames for meta in that case.
+func (h *Hub) RegisterStream(meta StreamMeta) (ok bool) {
+	cfg := h.roomConfig(meta.Room)
+	if cfg.MaxDevices > 0 && h.deviceCount(meta.Room) >= cfg.MaxDevices {
+		return false
+	}
+	if !cfg.allowsCodec(meta.Codec) {
+		return false
+	}
 
-		// Pumping frames to the viewers
-		var failConns []*websocket.Conn
-		for _, v := range viewers {
-			if err := safeWriteMessage(v, websocket.BinaryMessage, payload); err != nil {
-				failConns = append(failConns, v)
-			}
+	h.mu.Lock()
+	meta.LastSeen = time.Now().UnixMilli()
+	h.metas[meta.DeviceID] = meta
+
+	var roomViewers []*websocket.Conn
+	if vset := h.viewers[meta.Room]; vset != nil {
+		for v := range vset {
+			roomViewers = append(roomViewers, v)
 		}
+	}
+	h.mu.Unlock()
 
-		// Removing dead fail connections
-		if len(failConns) > 0 {
-			h.mu.Lock()
-			for _, v := range failConns {
-				delete(h.viewers[meta.Room], v)
-				forgetConn(v)
-			}
-			h.mu.Unlock()
+	if len(roomViewers) > 0 {
+		event := map[string]any{"type": "join", "stream": meta}
+		for _, viewerConn := range roomViewers {
+			_ = safeWriteJSON(viewerConn, event)
 		}
 	}
+	return true
+}
 
-	// Cleaning up disconnects
+// UnregisterStream removes meta's device from the live set and notifies
+// viewers it's gone with a "leave" event, mirroring the disconnect cleanup
+// HandleIngest did inline. Call it once the device stops publishing.
+func (h *Hub) UnregisterStream(meta StreamMeta) {
 	h.mu.Lock()
-	delete(h.ingest, meta.DeviceID)
 	delete(h.metas, meta.DeviceID)
 
-	// Collecting viewers
 	var viewers []*websocket.Conn
 	if vset := h.viewers[meta.Room]; vset != nil {
 		for v := range vset {
 			viewers = append(viewers, v)
 		}
 	}
-
 	h.mu.Unlock()
 
-	// Notifiying existing viewers about deviceID is disconnected
 	if len(viewers) > 0 {
 		event := map[string]any{"type": "leave", "device_id": meta.DeviceID}
 		for _, v := range viewers {
@@ -241,32 +305,173 @@ func (h *Hub) HandleIngest(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// publishFrame fans a single frame out to the stream router and every
+// WebSocket viewer of meta.Room, exactly as if it had arrived on /ingest.
+// Shared by HandleIngest and RTSPSource so pulled cameras look identical
+// to pushed ones downstream.
+func (h *Hub) publishFrame(meta StreamMeta, frame []byte) {
+	h.router.Publish(meta, frame)
+
+	header := make([]byte, 2+len(meta.DeviceID))
+	binary.BigEndian.PutUint16(header[:2], uint16(len(meta.DeviceID)))
+	copy(header[2:], []byte(meta.DeviceID))
+
+	payload := append(header, frame...)
+	keyFrame := isH264Keyframe(frame)
+
+	h.mu.RLock()
+	for _, vw := range h.viewers[meta.Room] {
+		vw.enqueue(payload, keyFrame)
+	}
+	h.mu.RUnlock()
+}
+
+// removeViewer unregisters and closes a viewer, idempotently — it's called
+// both from HandleView's cleanup and from a viewer's own pump goroutine
+// after too many consecutive write failures.
+func (h *Hub) removeViewer(room string, c *websocket.Conn) {
+	h.mu.Lock()
+	vw, ok := h.viewers[room][c]
+	if ok {
+		delete(h.viewers[room], c)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		close(vw.queue)
+		forgetConn(c)
+	}
+}
+
+// HandleMintToken mints a signed token for /ingest or /view, gated by a
+// bootstrap admin key configured via CAMFEED_ADMIN_KEY. With no admin key
+// configured, minting is disabled entirely rather than left open.
+func (h *Hub) HandleMintToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminKey := os.Getenv("CAMFEED_ADMIN_KEY")
+	if adminKey == "" || r.Header.Get("Authorization") != "Bearer "+adminKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Room       string `json:"room"`
+		DeviceID   string `json:"device_id,omitempty"`
+		Role       Role   `json:"role"`
+		TTLSeconds int64  `json:"ttl_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Room == "" || (req.Role != RolePublish && req.Role != RoleView) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if req.TTLSeconds <= 0 {
+		req.TTLSeconds = 3600
+	}
+
+	token, err := h.auth.Mint(TokenClaims{
+		Room:     req.Room,
+		DeviceID: req.DeviceID,
+		Role:     req.Role,
+		Exp:      time.Now().Add(time.Duration(req.TTLSeconds) * time.Second).Unix(),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+// HandleAddSource registers and starts pulling a new upstream RTSP source
+// (an ONVIF/IP camera that can't speak our WebSocket ingest protocol),
+// publishing its frames as if they'd arrived on /ingest. Since it makes the
+// server dial an arbitrary caller-supplied URL and can steal any existing
+// device_id out from under its current source, it's gated by the same
+// bootstrap admin key as HandleMintToken rather than left open.
+func (h *Hub) HandleAddSource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminKey := os.Getenv("CAMFEED_ADMIN_KEY")
+	if adminKey == "" || r.Header.Get("Authorization") != "Bearer "+adminKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var cfg RTSPSourceConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil || cfg.DeviceID == "" || cfg.URL == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if cfg.Room == "" {
+		cfg.Room = DefaultRoom
+	}
+
+	parent := h.serveCtx
+	if parent == nil {
+		parent = r.Context()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	h.mu.Lock()
+	if stop, ok := h.sources[cfg.DeviceID]; ok {
+		stop()
+	}
+	h.sources[cfg.DeviceID] = cancel
+	h.mu.Unlock()
+
+	go newRTSPSource(cfg, h).run(ctx)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
 func (h *Hub) HandleView(w http.ResponseWriter, r *http.Request) {
 	room := r.URL.Query().Get("room")
 	if strings.TrimSpace(room) == "" {
 		room = DefaultRoom
 	}
 
+	if _, err := h.auth.Validate(r.URL.Query().Get("token"), room, RoleView); err != nil {
+		log.Printf("view auth rejected for room %q: %v", room, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	cfg := h.roomConfig(room)
+	h.mu.RLock()
+	viewerCount := len(h.viewers[room])
+	h.mu.RUnlock()
+	if cfg.MaxViewers > 0 && viewerCount >= cfg.MaxViewers {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
 	c, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
-	defer func() {
-		h.mu.Lock()
-		if h.viewers[room] != nil {
-			delete(h.viewers[room], c)
-		}
-		h.mu.Unlock()
-		forgetConn(c)
-	}()
+	defer h.removeViewer(room, c)
 
 	// register viewer
+	vw := newViewer(c)
 	h.mu.Lock()
 	if h.viewers[room] == nil {
-		h.viewers[room] = map[*websocket.Conn]bool{}
+		h.viewers[room] = map[*websocket.Conn]*viewer{}
 	}
-	h.viewers[room][c] = true
+	h.viewers[room][c] = vw
+	h.mu.Unlock()
+
+	go vw.pump(h, room)
+
+	h.mu.Lock()
 
 	// Sending manifest: list of all devices connected
 	var deviceList []StreamMeta
@@ -308,27 +513,47 @@ func (h *Hub) HandleView(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ViewerStat reports per-viewer backpressure stats, e.g. for /manifest.
+type ViewerStat struct {
+	Room    string `json:"room"`
+	ID      string `json:"id"`
+	Dropped uint64 `json:"dropped"`
+}
+
 // Endpoint to list down all the metadata from all the camera
 func (h *Hub) HandleManifest(w http.ResponseWriter, r *http.Request) {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
-
 	var list []StreamMeta
 	for _, m := range h.metas {
 		list = append(list, m)
 	}
 
+	var viewerStats []ViewerStat
+	for room, vset := range h.viewers {
+		for _, vw := range vset {
+			viewerStats = append(viewerStats, ViewerStat{Room: room, ID: vw.id, Dropped: vw.dropCount.Load()})
+		}
+	}
+	h.mu.RUnlock()
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{"type": "manifest", "stream": list})
+	_ = json.NewEncoder(w).Encode(map[string]any{"type": "manifest", "stream": list, "viewers": viewerStats})
 }
 
 func (h *Hub) StartServers(ctx context.Context) error {
+	h.serveCtx = ctx
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthcheck", h.HealthCheck)
 	mux.HandleFunc("/", h.HealthCheck)
 	mux.HandleFunc("/ingest", h.HandleIngest) // used by phones (WSS on :6699)
 	mux.HandleFunc("/view", h.HandleView)     // used by Wails viewer (WS on :6698)
 	mux.HandleFunc("/manifest", h.HandleManifest)
+	mux.HandleFunc("/sources", h.HandleAddSource)          // pull-mode RTSP camera sources
+	mux.HandleFunc("/tokens", h.HandleMintToken)           // mints /ingest and /view auth tokens
+	mux.HandleFunc("/rooms/config", h.HandleSetRoomConfig) // sets per-room connect-time limits
+	mux.HandleFunc("/whip", h.HandleWHIP)                  // WHIP publish: POST offer -> answer
+	mux.HandleFunc("/whip/", h.HandleWHIP)                 // WHIP teardown: DELETE /whip/<resource>
 	mux.HandleFunc("/camera", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFileFS(w, r, staticFS, "static/camera.html")
 	})
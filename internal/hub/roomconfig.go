@@ -0,0 +1,92 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// RoomConfig caps what a room will accept, checked at connect time on
+// /ingest and /view. The zero value imposes no limits and allows any
+// codec, so rooms work unconfigured by default.
+type RoomConfig struct {
+	MaxDevices    int
+	MaxViewers    int
+	AllowedCodecs []string // empty means any codec is allowed
+}
+
+func (cfg RoomConfig) allowsCodec(codec string) bool {
+	if codec == "" || len(cfg.AllowedCodecs) == 0 {
+		return true
+	}
+	for _, c := range cfg.AllowedCodecs {
+		if strings.EqualFold(c, codec) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRoomConfig installs (or replaces) the connect-time limits for room.
+func (h *Hub) SetRoomConfig(room string, cfg RoomConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.roomConfigs[room] = cfg
+}
+
+// HandleSetRoomConfig lets an operator install per-room connect-time
+// limits, gated by the same bootstrap admin key as HandleMintToken and
+// HandleAddSource. A room with no config installed runs with the zero
+// value (no limits).
+func (h *Hub) HandleSetRoomConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	adminKey := os.Getenv("CAMFEED_ADMIN_KEY")
+	if adminKey == "" || r.Header.Get("Authorization") != "Bearer "+adminKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Room          string   `json:"room"`
+		MaxDevices    int      `json:"max_devices"`
+		MaxViewers    int      `json:"max_viewers"`
+		AllowedCodecs []string `json:"allowed_codecs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Room == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.SetRoomConfig(req.Room, RoomConfig{
+		MaxDevices:    req.MaxDevices,
+		MaxViewers:    req.MaxViewers,
+		AllowedCodecs: req.AllowedCodecs,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Hub) roomConfig(room string) RoomConfig {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.roomConfigs[room]
+}
+
+// deviceCount returns how many devices are currently publishing into room.
+func (h *Hub) deviceCount(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := 0
+	for _, m := range h.metas {
+		if m.Room == room {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package hub
+
+import "sync"
+
+// StreamRouter is the shared bus between the WebSocket ingest path and
+// anything that wants to consume the same frames (currently the RTSP and
+// HLS servers). HandleIngest publishes every frame it reads from a device onto
+// the router, keyed by room/deviceID, and any number of subscribers can
+// fan out from there independently of the WebSocket viewer path.
+type StreamRouter struct {
+	mu      sync.RWMutex
+	streams map[string]*routerStream
+}
+
+type routerStream struct {
+	meta StreamMeta
+	subs map[chan []byte]bool
+}
+
+func NewStreamRouter() *StreamRouter {
+	return &StreamRouter{streams: map[string]*routerStream{}}
+}
+
+func streamKey(room, deviceID string) string {
+	return room + "/" + deviceID
+}
+
+// Publish records the latest meta for room/deviceID and fans the frame out
+// to every current subscriber. Subscribers own their own backpressure, so a
+// full subscriber channel just drops the frame rather than blocking here.
+func (r *StreamRouter) Publish(meta StreamMeta, frame []byte) {
+	key := streamKey(meta.Room, meta.DeviceID)
+
+	r.mu.Lock()
+	s := r.streams[key]
+	if s == nil {
+		s = &routerStream{subs: map[chan []byte]bool{}}
+		r.streams[key] = s
+	}
+	s.meta = meta
+
+	var subs []chan []byte
+	for ch := range s.subs {
+		subs = append(subs, ch)
+	}
+	r.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a new subscriber for room/deviceID. The returned
+// unsubscribe func must be called once the subscriber is done, or the
+// channel leaks.
+func (r *StreamRouter) Subscribe(room, deviceID string) (<-chan []byte, func()) {
+	key := streamKey(room, deviceID)
+	ch := make(chan []byte, 32)
+
+	r.mu.Lock()
+	s := r.streams[key]
+	if s == nil {
+		s = &routerStream{subs: map[chan []byte]bool{}}
+		r.streams[key] = s
+	}
+	s.subs[ch] = true
+	r.mu.Unlock()
+
+	unsub := func() {
+		r.mu.Lock()
+		if s := r.streams[key]; s != nil {
+			delete(s.subs, ch)
+		}
+		r.mu.Unlock()
+	}
+
+	return ch, unsub
+}
+
+// Meta returns the most recently published metadata for room/deviceID.
+func (r *StreamRouter) Meta(room, deviceID string) (StreamMeta, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s := r.streams[streamKey(room, deviceID)]
+	if s == nil {
+		return StreamMeta{}, false
+	}
+	return s.meta, true
+}
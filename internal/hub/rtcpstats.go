@@ -0,0 +1,102 @@
+package hub
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/HOTSONHONET/cam-feed/pkg/rtp"
+	"github.com/HOTSONHONET/cam-feed/pkg/rtp/jitter"
+)
+
+// rtpClockRate is the RTP timestamp clock rate for H.264, the only codec
+// the ingest paths below handle (RFC 6184 section 8.2.1).
+const rtpClockRate = 90000
+
+// rtcpReportInterval is how often an ingest path sends back an RTCP
+// receiver report summarizing what it's seen so far.
+const rtcpReportInterval = 5 * time.Second
+
+// receptionTracker accumulates loss and jitter stats for one inbound RTP
+// stream, so the ingest paths that actually receive media (a pulled RTSP
+// camera, a WHIP publisher) can send real RTCP receiver reports instead of
+// none at all. We never send media back on these links, so there's no
+// sender info to report — only RR, never SR (RFC 3550 section 6.4).
+type receptionTracker struct {
+	reporterSSRC uint32
+
+	mu         sync.Mutex
+	est        jitter.Estimator
+	jitter     uint32
+	ssrc       uint32
+	haveFirst  bool
+	baseSeq    uint16
+	highestSeq uint16
+	cycles     uint32
+	received   uint32
+}
+
+func newReceptionTracker() *receptionTracker {
+	return &receptionTracker{reporterSSRC: rand.Uint32()}
+}
+
+// update folds in one received RTP packet's sequence number and timestamp.
+func (t *receptionTracker) update(ssrc uint32, seq uint16, timestamp uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ssrc = ssrc
+	if !t.haveFirst {
+		t.baseSeq = seq
+		t.highestSeq = seq
+		t.haveFirst = true
+	} else if int16(seq-t.highestSeq) > 0 {
+		if seq < t.highestSeq {
+			t.cycles++
+		}
+		t.highestSeq = seq
+	}
+	t.received++
+
+	arrival := uint32(uint64(time.Now().UnixNano()) * rtpClockRate / uint64(time.Second))
+	t.jitter = t.est.Update(timestamp, arrival)
+}
+
+// stats computes an RTCP reception report block from what's been seen so
+// far (RFC 3550 section 6.4.1), or ok=false if no packet has arrived yet.
+func (t *receptionTracker) stats() (stats rtp.ReceptionStats, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.haveFirst {
+		return rtp.ReceptionStats{}, false
+	}
+
+	extHighest := t.cycles<<16 | uint32(t.highestSeq)
+	expected := extHighest - uint32(t.baseSeq) + 1
+	var lost uint32
+	if expected > t.received {
+		lost = expected - t.received
+	}
+	var fraction uint8
+	if expected > 0 {
+		fraction = uint8((lost * 256) / expected)
+	}
+
+	return rtp.ReceptionStats{
+		SourceSSRC:   t.ssrc,
+		PacketsLost:  lost,
+		FractionLost: fraction,
+		HighestSeq:   extHighest,
+		Jitter:       t.jitter,
+	}, true
+}
+
+// buildReceiverReport returns an RTCP RR packet summarizing what's been
+// seen so far, or ok=false if no packet has arrived yet to report on.
+func (t *receptionTracker) buildReceiverReport() (pkt []byte, ok bool) {
+	stats, ok := t.stats()
+	if !ok {
+		return nil, false
+	}
+	return rtp.BuildReceiverReport(t.reporterSSRC, stats), true
+}
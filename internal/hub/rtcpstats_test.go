@@ -0,0 +1,90 @@
+package hub
+
+import "testing"
+
+func TestReceptionTrackerStatsNoPacketsYet(t *testing.T) {
+	tracker := newReceptionTracker()
+	if _, ok := tracker.stats(); ok {
+		t.Fatalf("stats() ok=true before any packet arrived")
+	}
+}
+
+func TestReceptionTrackerStatsNoLoss(t *testing.T) {
+	tracker := newReceptionTracker()
+	for _, seq := range []uint16{10, 11, 12, 13} {
+		tracker.update(0xABCD, seq, uint32(seq))
+	}
+
+	stats, ok := tracker.stats()
+	if !ok {
+		t.Fatalf("stats() ok=false")
+	}
+	if stats.SourceSSRC != 0xABCD {
+		t.Fatalf("SourceSSRC = %#x, want %#x", stats.SourceSSRC, 0xABCD)
+	}
+	if stats.PacketsLost != 0 {
+		t.Fatalf("PacketsLost = %d, want 0", stats.PacketsLost)
+	}
+	if stats.FractionLost != 0 {
+		t.Fatalf("FractionLost = %d, want 0", stats.FractionLost)
+	}
+	if stats.HighestSeq != 13 {
+		t.Fatalf("HighestSeq = %d, want 13", stats.HighestSeq)
+	}
+}
+
+func TestReceptionTrackerStatsWithLoss(t *testing.T) {
+	tracker := newReceptionTracker()
+	// base=10, highest=14, so 5 expected (10..14), only 3 received -> 2 lost
+	for _, seq := range []uint16{10, 11, 14} {
+		tracker.update(1, seq, uint32(seq))
+	}
+
+	stats, ok := tracker.stats()
+	if !ok {
+		t.Fatalf("stats() ok=false")
+	}
+	if stats.PacketsLost != 2 {
+		t.Fatalf("PacketsLost = %d, want 2", stats.PacketsLost)
+	}
+	wantFraction := uint8((2 * 256) / 5)
+	if stats.FractionLost != wantFraction {
+		t.Fatalf("FractionLost = %d, want %d", stats.FractionLost, wantFraction)
+	}
+}
+
+func TestReceptionTrackerStatsSeqWraparound(t *testing.T) {
+	tracker := newReceptionTracker()
+	// base=0xFFFE, wraps to 0x0001 -> one cycle, extended highest = 1<<16 | 1
+	for _, seq := range []uint16{0xFFFE, 0xFFFF, 0x0000, 0x0001} {
+		tracker.update(1, seq, uint32(seq))
+	}
+
+	stats, ok := tracker.stats()
+	if !ok {
+		t.Fatalf("stats() ok=false")
+	}
+	wantHighest := uint32(1)<<16 | 1
+	if stats.HighestSeq != wantHighest {
+		t.Fatalf("HighestSeq = %#x, want %#x", stats.HighestSeq, wantHighest)
+	}
+	if stats.PacketsLost != 0 {
+		t.Fatalf("PacketsLost = %d, want 0 (all 4 packets received)", stats.PacketsLost)
+	}
+}
+
+func TestReceptionTrackerBuildReceiverReport(t *testing.T) {
+	tracker := newReceptionTracker()
+	if _, ok := tracker.buildReceiverReport(); ok {
+		t.Fatalf("buildReceiverReport ok=true before any packet arrived")
+	}
+
+	tracker.update(1, 0, 0)
+	pkt, ok := tracker.buildReceiverReport()
+	if !ok {
+		t.Fatalf("buildReceiverReport ok=false after a packet arrived")
+	}
+	if len(pkt) == 0 {
+		t.Fatalf("buildReceiverReport returned an empty packet")
+	}
+}
@@ -0,0 +1,100 @@
+package hub
+
+import (
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// viewerQueueCapacity bounds how many frames a viewer can lag behind by
+// before we start dropping, so a congested viewer can never stall the
+// ingest read loop.
+const viewerQueueCapacity = 4
+
+// maxConsecutiveWriteErrors is how many back-to-back write failures (each
+// already bounded by safeWriteMessage's write deadline) we tolerate before
+// giving up on a viewer.
+const maxConsecutiveWriteErrors = 3
+
+// viewer wraps a registered WebSocket viewer with its own goroutine and
+// bounded send queue, so one slow viewer can never block frame delivery to
+// the others (the classic SFU backpressure pattern).
+type viewer struct {
+	id   string
+	conn *websocket.Conn
+
+	queue     chan []byte
+	dropCount atomic.Uint64
+}
+
+func newViewer(c *websocket.Conn) *viewer {
+	return &viewer{
+		id:    c.RemoteAddr().String(),
+		conn:  c,
+		queue: make(chan []byte, viewerQueueCapacity),
+	}
+}
+
+// enqueue non-blockingly queues frame for delivery. If the queue is full,
+// the oldest queued frame is dropped to make room — unless frame isn't a
+// keyframe and the oldest queued one is, in which case the keyframe is
+// kept and frame is dropped instead.
+func (v *viewer) enqueue(frame []byte, keyFrame bool) {
+	select {
+	case v.queue <- frame:
+		return
+	default:
+	}
+
+	select {
+	case oldest := <-v.queue:
+		if !keyFrame && isH264Keyframe(oldest) {
+			select {
+			case v.queue <- oldest:
+			default:
+			}
+			v.dropCount.Add(1)
+			return
+		}
+		v.dropCount.Add(1)
+	default:
+	}
+
+	select {
+	case v.queue <- frame:
+	default:
+		v.dropCount.Add(1)
+	}
+}
+
+// pump drains the send queue and writes frames to the viewer's WebSocket
+// connection, removing the viewer after too many consecutive write
+// failures rather than on the first one.
+func (v *viewer) pump(h *Hub, room string) {
+	consecutiveErrs := 0
+	for frame := range v.queue {
+		if err := safeWriteMessage(v.conn, websocket.BinaryMessage, frame); err != nil {
+			consecutiveErrs++
+			if consecutiveErrs >= maxConsecutiveWriteErrors {
+				h.removeViewer(room, v.conn)
+				return
+			}
+			continue
+		}
+		consecutiveErrs = 0
+	}
+}
+
+// isH264Keyframe reports whether an Annex-B access unit carries an
+// SPS, PPS or IDR NAL — the frames we least want to drop.
+func isH264Keyframe(frame []byte) bool {
+	for i := 0; i+3 < len(frame); i++ {
+		if frame[i] == 0 && frame[i+1] == 0 && frame[i+2] == 1 {
+			switch frame[i+3] & 0x1F {
+			case 5, 7, 8: // IDR, SPS, PPS
+				return true
+			}
+		}
+	}
+	return false
+}
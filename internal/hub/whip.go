@@ -0,0 +1,226 @@
+package hub
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+// whipSession is one active WHIP publish, tracked by resource ID so DELETE
+// can tear the right one down and re-validate the publish token for its room.
+type whipSession struct {
+	pc   *webrtc.PeerConnection
+	room string
+	meta StreamMeta
+}
+
+var (
+	whipSessions   = map[string]*whipSession{}
+	whipSessionsMu sync.Mutex
+)
+
+// HandleWHIP implements the publish side of WHIP (draft-ietf-wish-whip):
+// POST an SDP offer and get back an SDP answer plus a Location resource
+// URL; DELETE that resource to tear the session down. Received video is
+// depacketized and published through the same path as /ingest, so
+// WebSocket viewers and the RTSP server see WHIP streams identically.
+func (h *Hub) HandleWHIP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		h.handleWHIPDelete(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	room := r.URL.Query().Get("room")
+	if strings.TrimSpace(room) == "" {
+		room = DefaultRoom
+	}
+
+	claims, err := h.auth.Validate(r.URL.Query().Get("token"), room, RolePublish)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	deviceID := r.URL.Query().Get("device_id")
+	if deviceID == "" {
+		deviceID = "whip-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	if claims.DeviceID != "" && claims.DeviceID != deviceID {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	offer, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeVideo, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+		pc.Close()
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if _, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+		pc.Close()
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	meta := StreamMeta{DeviceID: deviceID, Room: room, Codec: "h264", LastSeen: time.Now().UnixMilli()}
+
+	// Register with the hub the same way HandleIngest does, so this WHIP
+	// stream is discoverable via HandleManifest/the viewer "join" event and
+	// subject to the room's ACLs, instead of only pushing frames silently.
+	if !h.RegisterStream(meta) {
+		pc.Close()
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		if track.Kind() == webrtc.RTPCodecTypeVideo {
+			go pumpWHIPTrack(track, pc, h, meta)
+		}
+	})
+
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: string(offer)}); err != nil {
+		h.UnregisterStream(meta)
+		pc.Close()
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		h.UnregisterStream(meta)
+		pc.Close()
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		h.UnregisterStream(meta)
+		pc.Close()
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	<-gatherComplete
+
+	resourceID := deviceID + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	whipSessionsMu.Lock()
+	whipSessions[resourceID] = &whipSession{pc: pc, room: room, meta: meta}
+	whipSessionsMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", "/whip/"+resourceID)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(pc.LocalDescription().SDP))
+}
+
+func (h *Hub) handleWHIPDelete(w http.ResponseWriter, r *http.Request) {
+	resourceID := strings.TrimPrefix(r.URL.Path, "/whip/")
+
+	whipSessionsMu.Lock()
+	sess, ok := whipSessions[resourceID]
+	whipSessionsMu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// resourceID is guessable (device_id + timestamp), so require the same
+	// publish token POST does rather than letting anyone who can see it
+	// tear down someone else's session.
+	if _, err := h.auth.Validate(r.URL.Query().Get("token"), sess.room, RolePublish); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	whipSessionsMu.Lock()
+	delete(whipSessions, resourceID)
+	whipSessionsMu.Unlock()
+
+	h.UnregisterStream(sess.meta)
+	_ = sess.pc.Close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pumpWHIPTrack depacketizes a remote video track's RTP stream into
+// access units and publishes each one, same as hub.RTSPSource does for a
+// pulled camera. Alongside that, it tracks loss and jitter for the track
+// and periodically sends a real RTCP receiver report back to the browser.
+func pumpWHIPTrack(track *webrtc.TrackRemote, pc *webrtc.PeerConnection, h *Hub, meta StreamMeta) {
+	done := make(chan struct{})
+	defer close(done)
+
+	tracker := newReceptionTracker()
+	go reportReceptionLoopWHIP(done, tracker, pc)
+
+	var depkt h264Depacketizer
+	var accessUnit []byte
+
+	for {
+		pkt, _, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		tracker.update(uint32(track.SSRC()), pkt.SequenceNumber, pkt.Timestamp)
+
+		for _, nal := range depkt.feed(pkt.Payload) {
+			accessUnit = append(accessUnit, annexBStartCode...)
+			accessUnit = append(accessUnit, nal...)
+		}
+
+		if pkt.Marker && len(accessUnit) > 0 {
+			meta.LastSeen = time.Now().UnixMilli()
+			h.publishFrame(meta, accessUnit)
+			accessUnit = nil
+		}
+	}
+}
+
+// reportReceptionLoopWHIP periodically writes an RTCP receiver report for
+// tracker's track back to pc, until done is closed.
+func reportReceptionLoopWHIP(done <-chan struct{}, tracker *receptionTracker, pc *webrtc.PeerConnection) {
+	t := time.NewTicker(rtcpReportInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			stats, ok := tracker.stats()
+			if !ok {
+				continue
+			}
+			_ = pc.WriteRTCP([]rtcp.Packet{&rtcp.ReceiverReport{
+				SSRC: tracker.reporterSSRC,
+				Reports: []rtcp.ReceptionReport{{
+					SSRC:               stats.SourceSSRC,
+					FractionLost:       stats.FractionLost,
+					TotalLost:          stats.PacketsLost,
+					LastSequenceNumber: stats.HighestSeq,
+					Jitter:             stats.Jitter,
+				}},
+			}})
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package h264 holds small, codec-level H.264 helpers shared by the RTSP
+// and HLS ingest/egress paths, so they can't silently drift apart.
+package h264
+
+// SplitNALUnits splits an Annex-B byte stream (NAL units separated by
+// 00 00 01 / 00 00 00 01 start codes) into individual NAL units.
+func SplitNALUnits(stream []byte) [][]byte {
+	var starts []int
+	for i := 0; i+2 < len(stream); i++ {
+		if stream[i] == 0 && stream[i+1] == 0 && stream[i+2] == 1 {
+			starts = append(starts, i+3)
+		}
+	}
+	if len(starts) == 0 {
+		return [][]byte{stream}
+	}
+
+	var nals [][]byte
+	for idx, nalStart := range starts {
+		nalEnd := len(stream)
+		if idx+1 < len(starts) {
+			// back off the start code we just matched plus any
+			// leading zero padding before it
+			nalEnd = starts[idx+1] - 3
+			for nalEnd > nalStart && stream[nalEnd-1] == 0 {
+				nalEnd--
+			}
+		}
+		if nalEnd > nalStart {
+			nals = append(nals, stream[nalStart:nalEnd])
+		}
+	}
+	return nals
+}
@@ -0,0 +1,76 @@
+package hls
+
+import "encoding/binary"
+
+// sampleEntry is one fMP4 sample: an access unit's NALs concatenated in
+// AVCC form (each NAL prefixed by its 4-byte length, matching buildAvcC's
+// lengthSizeMinusOne=3), its duration in timescale units, and whether it's
+// a sync sample (keyframe).
+type sampleEntry struct {
+	data     []byte
+	duration uint32
+	keyframe bool
+}
+
+// buildFragment encodes one CMAF fragment (moof + mdat) for samples,
+// tagged with sequenceNumber and a baseMediaDecodeTime of baseTS.
+func buildFragment(sequenceNumber uint32, baseTS uint64, samples []sampleEntry) []byte {
+	mfhd := box("mfhd", concat([]byte{0, 0, 0, 0}, u32(sequenceNumber)))
+
+	tfhd := box("tfhd", concat(
+		[]byte{0, 0x02, 0, 0}, // flags: default-base-is-moof
+		u32(trackID),
+	))
+
+	tfdt := box("tfdt", concat([]byte{1, 0, 0, 0}, u64(baseTS))) // version 1: 64-bit baseMediaDecodeTime
+
+	trun := box("trun", trunBody(samples))
+	traf := box("traf", concat(tfhd, tfdt, trun))
+	moof := box("moof", concat(mfhd, traf))
+
+	// trun's data_offset is the byte distance from the start of moof to
+	// this fragment's sample data, which starts right after the mdat box
+	// header — patch it in now that moof's total size is known.
+	dataOffset := uint32(len(moof) + 8)
+	dataOffsetPos := 8 /* moof box header */ + len(mfhd) + 8 /* traf box header */ + len(tfhd) + len(tfdt) + 8 /* trun box header */ + 8 /* trun version/flags + sample_count */
+	binary.BigEndian.PutUint32(moof[dataOffsetPos:dataOffsetPos+4], dataOffset)
+
+	return concat(moof, buildMdat(samples))
+}
+
+// trunFlags enables data-offset, per-sample duration, per-sample size and
+// per-sample flags fields (ISO 14496-12 section 8.8.8.1).
+const trunFlags = 0x000001 | 0x000100 | 0x000200 | 0x000400
+
+func trunBody(samples []sampleEntry) []byte {
+	body := concat(
+		[]byte{0, byte(trunFlags >> 16), byte(trunFlags >> 8), byte(trunFlags & 0xFF)},
+		u32(uint32(len(samples))),
+		u32(0), // data_offset placeholder, patched by buildFragment
+	)
+
+	for _, s := range samples {
+		body = append(body, u32(s.duration)...)
+		body = append(body, u32(uint32(len(s.data)))...)
+		body = append(body, u32(sampleFlags(s.keyframe))...)
+	}
+	return body
+}
+
+// sampleFlags encodes ISO 14496-12 section 8.8.3.1's sample_flags: a
+// keyframe depends on no other sample and is a sync sample, a
+// non-keyframe depends on a preceding one and isn't.
+func sampleFlags(keyframe bool) uint32 {
+	if keyframe {
+		return 0x02000000
+	}
+	return 0x01010000
+}
+
+func buildMdat(samples []sampleEntry) []byte {
+	var body []byte
+	for _, s := range samples {
+		body = append(body, s.data...)
+	}
+	return box("mdat", body)
+}
@@ -0,0 +1,42 @@
+package hls
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// findBox returns the body of the first top-level box named name within data.
+func findBox(t *testing.T, data []byte, name string) []byte {
+	t.Helper()
+	for len(data) >= 8 {
+		size := binary.BigEndian.Uint32(data[:4])
+		boxType := string(data[4:8])
+		if boxType == name {
+			return data[8:size]
+		}
+		data = data[size:]
+	}
+	t.Fatalf("box %q not found", name)
+	return nil
+}
+
+func TestBuildFragmentDataOffset(t *testing.T) {
+	samples := []sampleEntry{
+		{data: []byte{0, 0, 0, 1, 0x65}, duration: 3000, keyframe: true},
+		{data: []byte{0, 0, 0, 1, 0x41}, duration: 3000, keyframe: false},
+	}
+
+	fragment := buildFragment(1, 0, samples)
+
+	moofBody := findBox(t, fragment, "moof")
+	trafBody := findBox(t, moofBody, "traf")
+	trun := findBox(t, trafBody, "trun")
+
+	// trun body: version/flags(4) + sample_count(4) + data_offset(4) + ...
+	dataOffset := binary.BigEndian.Uint32(trun[8:12])
+
+	wantOffset := uint32(len(moofBody) + 8 /* moof header */ + 8 /* mdat header */)
+	if dataOffset != wantOffset {
+		t.Fatalf("data_offset = %d, want %d (len(moof)+8)", dataOffset, wantOffset)
+	}
+}
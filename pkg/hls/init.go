@@ -0,0 +1,151 @@
+package hls
+
+const (
+	trackID       = 1
+	timescale     = 90000 // matches the 90kHz clock pkg/rtsp already uses
+	defaultWidth  = 1280
+	defaultHeight = 720
+)
+
+// buildInitSegment builds the fMP4 initialization segment (ftyp + moov)
+// describing a single H.264 video track, derived from the stream's SPS
+// and PPS.
+func buildInitSegment(sps, pps []byte) []byte {
+	width, height, ok := parseSPSDimensions(sps)
+	if !ok {
+		width, height = defaultWidth, defaultHeight
+	}
+
+	ftypBox := box("ftyp", concat(
+		[]byte("isom"), u32(0x200),
+		[]byte("isom"), []byte("iso6"), []byte("mp41"),
+	))
+
+	moovBox := box("moov", concat(
+		mvhdBox(),
+		trakBox(sps, pps, width, height),
+		mvexBox(),
+	))
+
+	return concat(ftypBox, moovBox)
+}
+
+func identityMatrix() []byte {
+	return concat(
+		u32(0x00010000), u32(0), u32(0),
+		u32(0), u32(0x00010000), u32(0),
+		u32(0), u32(0), u32(0x40000000),
+	)
+}
+
+func mvhdBox() []byte {
+	body := concat(
+		[]byte{0, 0, 0, 0}, // version/flags
+		u32(0), u32(0),     // creation/modification time
+		u32(timescale),
+		u32(0),              // duration: 0, length isn't known upfront in a fragmented file
+		u32(0x00010000),     // rate 1.0
+		u16(0x0100), u16(0), // volume 1.0, reserved
+		u32(0), u32(0), // reserved
+		identityMatrix(),
+		make([]byte, 24), // pre_defined
+		u32(trackID+1),   // next_track_ID
+	)
+	return box("mvhd", body)
+}
+
+func trakBox(sps, pps []byte, width, height int) []byte {
+	tkhd := box("tkhd", concat(
+		[]byte{0, 0, 0, 3}, // version 0, flags: track_enabled | track_in_movie
+		u32(0), u32(0),     // creation/modification time
+		u32(trackID),
+		u32(0),          // reserved
+		u32(0),          // duration
+		make([]byte, 8), // reserved
+		u16(0), u16(0),  // layer, alternate_group
+		u16(0), u16(0), // volume, reserved
+		identityMatrix(),
+		u32(uint32(width)<<16), u32(uint32(height)<<16),
+	))
+
+	mdhd := box("mdhd", concat(
+		[]byte{0, 0, 0, 0},
+		u32(0), u32(0),
+		u32(timescale),
+		u32(0),
+		u16(0x55c4), u16(0), // language "und", pre_defined
+	))
+
+	hdlr := box("hdlr", concat(
+		[]byte{0, 0, 0, 0},
+		u32(0),
+		[]byte("vide"),
+		make([]byte, 12),
+		[]byte("cam-feed video handler\x00"),
+	))
+
+	vmhd := box("vmhd", concat([]byte{0, 0, 0, 1}, u16(0), u16(0), u16(0), u16(0)))
+
+	urlBox := box("url ", []byte{0, 0, 0, 1})
+	dref := box("dref", concat([]byte{0, 0, 0, 0}, u32(1), urlBox))
+	dinf := box("dinf", dref)
+
+	avc1 := box("avc1", concat(
+		make([]byte, 6), u16(0), // reserved, data_reference_index
+		u16(0), u16(0), // pre_defined, reserved
+		make([]byte, 12), // pre_defined
+		u16(uint16(width)), u16(uint16(height)),
+		u32(0x00480000), u32(0x00480000), // horizontal/vertical resolution, 72dpi
+		u32(0),           // reserved
+		u16(1),           // frame_count
+		make([]byte, 32), // compressorname
+		u16(0x0018),      // depth
+		u16(0xFFFF),      // pre_defined
+		buildAvcC(sps, pps),
+	))
+
+	stsd := box("stsd", concat([]byte{0, 0, 0, 0}, u32(1), avc1))
+	stts := box("stts", concat([]byte{0, 0, 0, 0}, u32(0)))
+	stsc := box("stsc", concat([]byte{0, 0, 0, 0}, u32(0)))
+	stsz := box("stsz", concat([]byte{0, 0, 0, 0}, u32(0), u32(0)))
+	stco := box("stco", concat([]byte{0, 0, 0, 0}, u32(0)))
+	stbl := box("stbl", concat(stsd, stts, stsc, stsz, stco))
+
+	minf := box("minf", concat(vmhd, dinf, stbl))
+	mdia := box("mdia", concat(mdhd, hdlr, minf))
+
+	return box("trak", concat(tkhd, mdia))
+}
+
+func mvexBox() []byte {
+	trex := box("trex", concat(
+		[]byte{0, 0, 0, 0},
+		u32(trackID),
+		u32(1), // default_sample_description_index
+		u32(0), // default_sample_duration
+		u32(0), // default_sample_size
+		u32(0), // default_sample_flags
+	))
+	return box("mvex", trex)
+}
+
+// buildAvcC encodes the AVCDecoderConfigurationRecord (ISO 14496-15
+// section 5.2.4.1) from one SPS and one PPS, declaring 4-byte NAL lengths
+// so samples can use the AVCC (length-prefixed) format in the mdat.
+func buildAvcC(sps, pps []byte) []byte {
+	body := []byte{
+		1,      // configurationVersion
+		sps[1], // profile_idc
+		sps[2], // profile_compatibility
+		sps[3], // level_idc
+		0xFF,   // reserved(6) | lengthSizeMinusOne=3
+		0xE1,   // reserved(3) | numOfSequenceParameterSets=1
+	}
+	body = append(body, u16(uint16(len(sps)))...)
+	body = append(body, sps...)
+	body = append(body, 1) // numOfPictureParameterSets
+	body = append(body, u16(uint16(len(pps)))...)
+	body = append(body, pps...)
+
+	return box("avcC", body)
+}
@@ -0,0 +1,327 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HOTSONHONET/cam-feed/internal/hub"
+	"github.com/HOTSONHONET/cam-feed/pkg/h264"
+)
+
+// windowSize is how many completed segments are kept in memory for a
+// sliding-window playlist; nothing is ever written to disk.
+const windowSize = 10
+
+// targetSegmentDurationTS / targetPartDurationTS bound how much media a
+// segment / LL-HLS part holds, in timescale units.
+const (
+	targetSegmentDurationTS = timescale * 1 // ~1s segments
+	targetPartDurationTS    = timescale / 5 // ~200ms parts
+)
+
+// blockingRequestTimeout caps how long a _HLS_msn/_HLS_part blocking
+// playlist or part request waits before being answered with whatever is
+// available.
+const blockingRequestTimeout = 10 * time.Second
+
+// Part is one LL-HLS partial segment: a byte range of its Segment's Data
+// holding one CMAF fragment (moof+mdat).
+type Part struct {
+	Offset      int
+	Length      int
+	Duration    time.Duration
+	Independent bool
+}
+
+// Segment is a sliding-window HLS media segment. While being built, Data
+// grows with each finished Part; once Complete, it holds the whole
+// segment and can be served as one .m4s file to regular HLS clients.
+type Segment struct {
+	Sequence int
+	Data     []byte
+	Duration time.Duration
+	Parts    []Part
+	Complete bool
+}
+
+// Segmenter subscribes to one room/device's stream and muxes it into a
+// sliding window of fMP4 segments and LL-HLS parts.
+type Segmenter struct {
+	room, device string
+	router       *hub.StreamRouter
+
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	sps, pps []byte
+	init     []byte
+
+	segments []*Segment
+	curSeg   *Segment
+	nextSeq  int
+	fragSeq  uint32
+
+	curPartSamples []sampleEntry
+	curPartDur     uint32
+	curSegDur      uint32
+	partBaseTS     uint64
+	totalTS        uint64
+
+	lastFrame time.Time
+}
+
+func newSegmenter(router *hub.StreamRouter, room, device string) *Segmenter {
+	s := &Segmenter{router: router, room: room, device: device}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// run subscribes to the stream router and feeds every frame into the
+// segmenter until ctx is cancelled.
+func (s *Segmenter) run(ctx context.Context) {
+	frames, unsub := s.router.Subscribe(s.room, s.device)
+	defer unsub()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-frames:
+			if !ok {
+				return
+			}
+			s.ingest(frame)
+		}
+	}
+}
+
+// ingest processes one Annex-B access unit published by the stream
+// router, folding it into the current part/segment.
+func (s *Segmenter) ingest(frame []byte) {
+	nals := h264.SplitNALUnits(frame)
+	if len(nals) == 0 {
+		return
+	}
+
+	keyframe := false
+	var sampleData []byte
+	var sps, pps []byte
+
+	for _, nal := range nals {
+		switch nal[0] & 0x1F {
+		case 7:
+			sps = nal
+		case 8:
+			pps = nal
+		case 5:
+			keyframe = true
+		}
+		sampleData = append(sampleData, u32(uint32(len(nal)))...)
+		sampleData = append(sampleData, nal...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sps != nil {
+		s.sps = append([]byte(nil), sps...)
+	}
+	if pps != nil {
+		s.pps = append([]byte(nil), pps...)
+	}
+	if s.init == nil {
+		if s.sps == nil || s.pps == nil {
+			return // not enough to build an init segment yet
+		}
+		s.init = buildInitSegment(s.sps, s.pps)
+	}
+
+	now := time.Now()
+	if s.lastFrame.IsZero() {
+		s.lastFrame = now
+	}
+	durTS := uint32(now.Sub(s.lastFrame).Seconds() * timescale)
+	s.lastFrame = now
+	if durTS == 0 {
+		durTS = 1
+	}
+
+	if keyframe && s.curSeg != nil && s.curSegDur >= targetSegmentDurationTS {
+		s.finishPartLocked()
+		s.finishSegmentLocked()
+	}
+
+	if s.curSeg == nil {
+		if !keyframe {
+			return // wait for a keyframe so every segment starts clean
+		}
+		s.nextSeq++
+		s.curSeg = &Segment{Sequence: s.nextSeq}
+		s.curSegDur = 0
+	}
+
+	if len(s.curPartSamples) == 0 {
+		s.partBaseTS = s.totalTS
+	}
+	s.curPartSamples = append(s.curPartSamples, sampleEntry{data: sampleData, duration: durTS, keyframe: keyframe})
+	s.totalTS += uint64(durTS)
+	s.curPartDur += durTS
+	s.curSegDur += durTS
+
+	if s.curPartDur >= targetPartDurationTS {
+		s.finishPartLocked()
+	}
+}
+
+func (s *Segmenter) finishPartLocked() {
+	if len(s.curPartSamples) == 0 {
+		return
+	}
+
+	s.fragSeq++
+	frag := buildFragment(s.fragSeq, s.partBaseTS, s.curPartSamples)
+
+	s.curSeg.Parts = append(s.curSeg.Parts, Part{
+		Offset:      len(s.curSeg.Data),
+		Length:      len(frag),
+		Duration:    tsToDuration(s.curPartDur),
+		Independent: s.curPartSamples[0].keyframe,
+	})
+	s.curSeg.Data = append(s.curSeg.Data, frag...)
+
+	s.curPartSamples = nil
+	s.curPartDur = 0
+	s.cond.Broadcast()
+}
+
+func (s *Segmenter) finishSegmentLocked() {
+	s.curSeg.Complete = true
+	s.curSeg.Duration = tsToDuration(s.curSegDur)
+
+	s.segments = append(s.segments, s.curSeg)
+	if len(s.segments) > windowSize {
+		s.segments = s.segments[1:]
+	}
+
+	s.curSeg = nil
+	s.cond.Broadcast()
+}
+
+func tsToDuration(ts uint32) time.Duration {
+	return time.Duration(ts) * time.Second / timescale
+}
+
+// segmentBytesLocked returns the bytes for segment seq — the whole
+// (completed) segment if part < 0, or just that part's fragment
+// otherwise — and whether they're available yet.
+func (s *Segmenter) segmentBytesLocked(seq, part int) ([]byte, bool) {
+	seg := s.findSegmentLocked(seq)
+	if seg == nil {
+		return nil, false
+	}
+
+	if part < 0 {
+		if !seg.Complete {
+			return nil, false
+		}
+		return seg.Data, true
+	}
+	if part >= len(seg.Parts) {
+		return nil, false
+	}
+	p := seg.Parts[part]
+	return seg.Data[p.Offset : p.Offset+p.Length], true
+}
+
+func (s *Segmenter) findSegmentLocked(seq int) *Segment {
+	for _, seg := range s.segments {
+		if seg.Sequence == seq {
+			return seg
+		}
+	}
+	if s.curSeg != nil && s.curSeg.Sequence == seq {
+		return s.curSeg
+	}
+	return nil
+}
+
+// awaitSegment blocks until segment seq (and part, if >= 0) is available,
+// the request's context is cancelled, or blockingRequestTimeout elapses —
+// implementing the CAN-BLOCK-RELOAD semantics _HLS_msn/_HLS_part rely on.
+func (s *Segmenter) awaitSegment(ctx context.Context, seq, part int) {
+	done := make(chan struct{})
+	defer close(done)
+
+	timer := time.AfterFunc(blockingRequestTimeout, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.cond.Broadcast()
+			s.mu.Unlock()
+		case <-done:
+		}
+	}()
+
+	deadline := time.Now().Add(blockingRequestTimeout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if _, ok := s.segmentBytesLocked(seq, part); ok {
+			return
+		}
+		if ctx.Err() != nil || !time.Now().Before(deadline) {
+			return
+		}
+		s.cond.Wait()
+	}
+}
+
+// buildPlaylistLocked renders the current multivariant-free media
+// playlist: every completed segment in the window, plus EXT-X-PART
+// entries for whatever of the in-progress segment has been muxed so far.
+func (s *Segmenter) buildPlaylistLocked() string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:9\n")
+	b.WriteString("#EXT-X-TARGETDURATION:1\n")
+	fmt.Fprintf(&b, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", tsToDuration(targetPartDurationTS).Seconds())
+	b.WriteString("#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=0.6\n")
+	b.WriteString("#EXT-X-MAP:URI=\"init.mp4\"\n")
+
+	firstSeq := 0
+	switch {
+	case len(s.segments) > 0:
+		firstSeq = s.segments[0].Sequence
+	case s.curSeg != nil:
+		firstSeq = s.curSeg.Sequence
+	}
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", firstSeq)
+
+	for _, seg := range s.segments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\nseg%d.m4s\n", seg.Duration.Seconds(), seg.Sequence)
+	}
+
+	if s.curSeg != nil {
+		for i, p := range s.curSeg.Parts {
+			fmt.Fprintf(&b, "#EXT-X-PART:DURATION=%.3f,URI=\"seg%d.m4s?part=%d\"", p.Duration.Seconds(), s.curSeg.Sequence, i)
+			if p.Independent {
+				b.WriteString(",INDEPENDENT=YES")
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"seg%d.m4s?part=%d\"\n", s.curSeg.Sequence, len(s.curSeg.Parts))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,164 @@
+// Package hls provides HLS and LL-HLS (fMP4) egress: it subscribes to the
+// same hub.StreamRouter used by the WebSocket, RTSP and WHIP paths and
+// muxes each room/device's H.264 stream into a sliding window of CMAF
+// segments served over plain HTTP, so browsers and Safari can play a
+// stream without a WebSocket connection.
+package hls
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/HOTSONHONET/cam-feed/internal/hub"
+)
+
+var (
+	segmenters   = map[string]*Segmenter{}
+	segmentersMu sync.Mutex
+)
+
+// Serve starts the HLS egress HTTP server on addr. Segmenters are created
+// lazily, the first time a room/device is requested, and subscribe to
+// router from then on. Requests are gated by auth the same way /view is.
+// It blocks until the listener fails or ctx is cancelled.
+func Serve(ctx context.Context, addr string, router *hub.StreamRouter, auth hub.Auth) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hls/", func(w http.ResponseWriter, r *http.Request) {
+		handleRequest(ctx, router, auth, w, r)
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	return srv.ListenAndServe()
+}
+
+func handleRequest(ctx context.Context, router *hub.StreamRouter, auth hub.Auth, w http.ResponseWriter, r *http.Request) {
+	room, device, file, ok := parseHLSPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := auth.Validate(r.URL.Query().Get("token"), room, hub.RoleView); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	seg := getOrCreateSegmenter(ctx, router, room, device)
+
+	switch {
+	case file == "init.mp4":
+		seg.serveInit(w, r)
+	case file == "index.m3u8":
+		seg.servePlaylist(w, r)
+	case strings.HasPrefix(file, "seg") && strings.HasSuffix(file, ".m4s"):
+		seg.serveSegment(w, r, file)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// parseHLSPath splits "/hls/<room>/<device>/<file>" into its parts.
+func parseHLSPath(path string) (room, device, file string, ok bool) {
+	path = strings.TrimPrefix(path, "/hls/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+func getOrCreateSegmenter(ctx context.Context, router *hub.StreamRouter, room, device string) *Segmenter {
+	key := room + "/" + device
+
+	segmentersMu.Lock()
+	defer segmentersMu.Unlock()
+
+	if s, ok := segmenters[key]; ok {
+		return s
+	}
+
+	s := newSegmenter(router, room, device)
+	segmenters[key] = s
+	go s.run(ctx)
+	return s
+}
+
+func (s *Segmenter) serveInit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	init := s.init
+	s.mu.Unlock()
+
+	if init == nil {
+		http.Error(w, "stream not ready", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	_, _ = w.Write(init)
+}
+
+func (s *Segmenter) serveSegment(w http.ResponseWriter, r *http.Request, file string) {
+	name := strings.TrimSuffix(strings.TrimPrefix(file, "seg"), ".m4s")
+	seq, err := strconv.Atoi(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	part := -1
+	if v := r.URL.Query().Get("part"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			part = n
+		}
+	}
+
+	s.awaitSegment(r.Context(), seq, part)
+
+	s.mu.Lock()
+	data, ok := s.segmentBytesLocked(seq, part)
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "segment not available", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(data)
+}
+
+func (s *Segmenter) servePlaylist(w http.ResponseWriter, r *http.Request) {
+	msn, part := -1, -1
+	if v := r.URL.Query().Get("_HLS_msn"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			msn = n
+		}
+	}
+	if v := r.URL.Query().Get("_HLS_part"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			part = n
+		}
+	}
+	if msn >= 0 {
+		s.awaitSegment(r.Context(), msn, part)
+	}
+
+	s.mu.Lock()
+	playlist := s.buildPlaylistLocked()
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write([]byte(playlist))
+}
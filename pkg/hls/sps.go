@@ -0,0 +1,190 @@
+package hls
+
+// bitReader reads H.264 RBSP bits MSB-first from a NAL payload with
+// emulation-prevention bytes already stripped.
+type bitReader struct {
+	data []byte
+	pos  int // bit position
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: stripEmulationPrevention(data)}
+}
+
+// stripEmulationPrevention removes the 0x03 byte H.264 inserts after
+// every 00 00 pair to prevent it from looking like a start code.
+func stripEmulationPrevention(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (r *bitReader) bit() uint32 {
+	if r.pos/8 >= len(r.data) {
+		return 0
+	}
+	v := (r.data[r.pos/8] >> (7 - uint(r.pos%8))) & 1
+	r.pos++
+	return uint32(v)
+}
+
+func (r *bitReader) bits(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		v = (v << 1) | r.bit()
+	}
+	return v
+}
+
+// ue reads an Exp-Golomb unsigned code (ITU-T H.264 section 9.1).
+func (r *bitReader) ue() uint32 {
+	zeros := 0
+	for r.bit() == 0 && zeros < 32 {
+		zeros++
+	}
+	if zeros == 0 {
+		return 0
+	}
+	return (1 << uint(zeros)) - 1 + r.bits(zeros)
+}
+
+func (r *bitReader) se() int32 {
+	v := r.ue()
+	if v%2 == 0 {
+		return -int32(v / 2)
+	}
+	return int32((v + 1) / 2)
+}
+
+// highProfileChroma lists profile_idc values whose SPS carries the
+// chroma_format_idc / scaling-matrix fields (ITU-T H.264 section 7.3.2.1.1).
+func highProfileChroma(profileIdc uint32) bool {
+	switch profileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134:
+		return true
+	}
+	return false
+}
+
+// parseSPSDimensions extracts the coded picture width/height (in pixels,
+// after cropping) from a raw SPS NAL unit (header byte included). Returns
+// ok=false if the SPS can't be parsed, in which case callers should fall
+// back to a default.
+func parseSPSDimensions(sps []byte) (width, height int, ok bool) {
+	if len(sps) < 4 {
+		return 0, 0, false
+	}
+
+	r := newBitReader(sps[1:]) // skip the NAL header byte
+	profileIdc := r.bits(8)
+	r.bits(8) // constraint flags + reserved
+	r.bits(8) // level_idc
+	r.ue()    // seq_parameter_set_id
+
+	chromaFormatIdc := uint32(1)
+	separateColourPlane := uint32(0)
+	if highProfileChroma(profileIdc) {
+		chromaFormatIdc = r.ue()
+		if chromaFormatIdc == 3 {
+			separateColourPlane = r.bits(1) // separate_colour_plane_flag
+		}
+		r.ue()    // bit_depth_luma_minus8
+		r.ue()    // bit_depth_chroma_minus8
+		r.bits(1) // qpprime_y_zero_transform_bypass_flag
+		if r.bits(1) == 1 {
+			n := 8
+			if chromaFormatIdc == 3 {
+				n = 12
+			}
+			for i := 0; i < n; i++ {
+				if r.bits(1) == 1 {
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					skipScalingList(r, size)
+				}
+			}
+		}
+	}
+
+	r.ue() // log2_max_frame_num_minus4
+	picOrderCntType := r.ue()
+	if picOrderCntType == 0 {
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	} else if picOrderCntType == 1 {
+		r.bits(1) // delta_pic_order_always_zero_flag
+		r.se()    // offset_for_non_ref_pic
+		r.se()    // offset_for_top_to_bottom_field
+		n := r.ue()
+		for i := uint32(0); i < n; i++ {
+			r.se() // offset_for_ref_frame[i]
+		}
+	}
+	r.ue()    // max_num_ref_frames
+	r.bits(1) // gaps_in_frame_num_value_allowed_flag
+
+	picWidthInMbsMinus1 := r.ue()
+	picHeightInMapUnitsMinus1 := r.ue()
+	frameMbsOnlyFlag := r.bits(1)
+	if frameMbsOnlyFlag == 0 {
+		r.bits(1) // mb_adaptive_frame_field_flag
+	}
+	r.bits(1) // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom uint32
+	if r.bits(1) == 1 { // frame_cropping_flag
+		cropLeft = r.ue()
+		cropRight = r.ue()
+		cropTop = r.ue()
+		cropBottom = r.ue()
+	}
+
+	frameHeightInMbs := (2 - frameMbsOnlyFlag) * (picHeightInMapUnitsMinus1 + 1)
+
+	// crop offsets are in chroma sample units (ITU-T H.264 section 7.4.2.1.1)
+	subWidthC, subHeightC := uint32(1), uint32(1) // monochrome or 4:4:4
+	if separateColourPlane == 0 {
+		switch chromaFormatIdc {
+		case 1: // 4:2:0
+			subWidthC, subHeightC = 2, 2
+		case 2: // 4:2:2
+			subWidthC = 2
+		}
+	}
+
+	width = int((picWidthInMbsMinus1+1)*16) - int((cropLeft+cropRight)*subWidthC)
+	height = int(frameHeightInMbs*16) - int((cropTop+cropBottom)*subHeightC*(2-frameMbsOnlyFlag))
+
+	if width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// skipScalingList advances r past one scaling_list() of the given size
+// (ITU-T H.264 section 7.3.2.1.1.1); we only need the SPS fields after it.
+func skipScalingList(r *bitReader, size int) {
+	last, next := int32(8), int32(8)
+	for j := 0; j < size; j++ {
+		if next != 0 {
+			delta := r.se()
+			next = (last + delta + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
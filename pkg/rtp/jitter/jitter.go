@@ -0,0 +1,43 @@
+// Package jitter estimates RTP interarrival jitter per RFC 3550 section
+// 6.4.1, for embedding in RTCP sender/receiver reports.
+package jitter
+
+// Estimator is a running RFC 3550 jitter estimate for a single SSRC. The
+// zero value is ready to use.
+type Estimator struct {
+	lastArrival uint32
+	lastTransit uint32
+	have        bool
+	jitter      float64
+}
+
+// Update feeds one packet's RTP timestamp and its arrival time (in the
+// same 90kHz-style units as the RTP timestamp) into the estimator and
+// returns the current jitter estimate.
+//
+// Per RFC 3550: if S(i) is the RTP timestamp from packet i and R(i) is
+// the time of arrival in RTP timestamp units, D(i,j) = (R(j)-R(i)) -
+// (S(j)-S(i)). J is then updated on every packet as
+// J += (|D(i-1,i)| - J) / 16.
+func (e *Estimator) Update(rtpTimestamp, arrival uint32) uint32 {
+	transit := arrival - rtpTimestamp
+
+	if e.have {
+		d := int32(transit - e.lastTransit)
+		if d < 0 {
+			d = -d
+		}
+		e.jitter += (float64(d) - e.jitter) / 16
+	}
+
+	e.lastTransit = transit
+	e.lastArrival = arrival
+	e.have = true
+
+	return uint32(e.jitter)
+}
+
+// Jitter returns the current estimate without consuming a new sample.
+func (e *Estimator) Jitter() uint32 {
+	return uint32(e.jitter)
+}
@@ -0,0 +1,61 @@
+package jitter
+
+import "testing"
+
+// TestEstimatorFirstPacketHasNoJitter covers the zero-value estimator: the
+// first sample has no prior transit time to diff against, so RFC 3550's
+// J += (|D|-J)/16 update must not run yet.
+func TestEstimatorFirstPacketHasNoJitter(t *testing.T) {
+	var e Estimator
+	if got := e.Update(0, 1000); got != 0 {
+		t.Fatalf("jitter after first packet = %d, want 0", got)
+	}
+}
+
+// TestEstimatorConstantSpacingStaysZero covers packets arriving exactly on
+// schedule (no network jitter): D(i,j) is always 0, so J should stay 0.
+func TestEstimatorConstantSpacingStaysZero(t *testing.T) {
+	var e Estimator
+	for i := uint32(0); i < 5; i++ {
+		rtpTimestamp := i * 3000
+		arrival := 1000 + i*3000
+		if got := e.Update(rtpTimestamp, arrival); got != 0 {
+			t.Fatalf("packet %d: jitter = %d, want 0", i, got)
+		}
+	}
+}
+
+// TestEstimatorUpdate covers the RFC 3550 section 6.4.1 recurrence itself
+// against hand-computed values: J += (|D(i-1,i)| - J) / 16, truncated to
+// uint32 on each call (as BuildReceiverReport's Jitter field requires).
+func TestEstimatorUpdate(t *testing.T) {
+	var e Estimator
+	steps := []struct {
+		rtpTimestamp, arrival uint32
+		want                  uint32
+	}{
+		{0, 1000, 0},      // first sample: no prior transit to diff against
+		{3000, 4200, 12},  // transit 1000->1200, D=200, J=0+(200-0)/16=12.5
+		{6000, 7200, 11},  // transit 1200->1200, D=0, J=12.5+(0-12.5)/16=11.71
+		{9000, 10600, 35}, // transit 1200->1600, D=400, J=11.71+(400-11.71)/16=35.99
+		{12000, 13600, 33},
+	}
+
+	for i, s := range steps {
+		if got := e.Update(s.rtpTimestamp, s.arrival); got != s.want {
+			t.Fatalf("step %d: Update(%d, %d) = %d, want %d", i, s.rtpTimestamp, s.arrival, got, s.want)
+		}
+	}
+}
+
+// TestJitterMatchesLastUpdate covers Jitter() returning the estimate
+// without consuming a new sample.
+func TestJitterMatchesLastUpdate(t *testing.T) {
+	var e Estimator
+	e.Update(0, 1000)
+	want := e.Update(3000, 1000+3000+160)
+
+	if got := e.Jitter(); got != want {
+		t.Fatalf("Jitter() = %d, want %d (last Update's return value)", got, want)
+	}
+}
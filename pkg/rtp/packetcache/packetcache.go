@@ -0,0 +1,63 @@
+// Package packetcache keeps a short ring buffer of recently sent RTP
+// packets per SSRC so NACKed sequence numbers can be retransmitted
+// without re-reading from the original source.
+package packetcache
+
+import "sync"
+
+// DefaultSize is how many packets the cache holds by default — enough to
+// cover a NACK round-trip at typical video bitrates.
+const DefaultSize = 512
+
+// Cache is a fixed-size ring of recent RTP packets, indexed by sequence
+// number modulo its size. It's safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	packets [][]byte
+	seqs    []uint16
+	valid   []bool
+	size    int
+}
+
+// New creates a Cache holding up to size packets. size <= 0 uses
+// DefaultSize.
+func New(size int) *Cache {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Cache{
+		packets: make([][]byte, size),
+		seqs:    make([]uint16, size),
+		valid:   make([]bool, size),
+		size:    size,
+	}
+}
+
+// Store records packet under seq, evicting whatever previously occupied
+// that slot.
+func (c *Cache) Store(seq uint16, packet []byte) {
+	buf := make([]byte, len(packet))
+	copy(buf, packet)
+
+	idx := int(seq) % c.size
+
+	c.mu.Lock()
+	c.packets[idx] = buf
+	c.seqs[idx] = seq
+	c.valid[idx] = true
+	c.mu.Unlock()
+}
+
+// Get returns the cached packet for seq, if it's still present and
+// hasn't been overwritten by a later packet that landed in the same slot.
+func (c *Cache) Get(seq uint16) ([]byte, bool) {
+	idx := int(seq) % c.size
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.valid[idx] || c.seqs[idx] != seq {
+		return nil, false
+	}
+	return c.packets[idx], true
+}
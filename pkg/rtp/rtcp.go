@@ -0,0 +1,117 @@
+// Package rtp holds small RTP/RTCP building blocks (packet cache, jitter
+// estimation in its subpackages, and the RTCP encode/decode used by
+// NACK-driven retransmission) shared by the RTSP and WebRTC ingest/egress
+// paths.
+package rtp
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	ptSenderReport   = 200
+	ptReceiverReport = 201
+	ptRTPFB          = 205 // RFC 4585 transport-layer feedback
+
+	fmtGenericNACK = 1 // RFC 4585 section 6.2.1
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch
+// (1900-01-01) and the Unix epoch (1970-01-01).
+const ntpEpochOffset = 2208988800
+
+// NTPTimestamp converts t into the 32.32 fixed-point NTP timestamp used by
+// RTCP sender reports.
+func NTPTimestamp(t time.Time) (sec, frac uint32) {
+	unix := t.UnixNano()
+	sec = uint32(unix/int64(time.Second)) + ntpEpochOffset
+	frac = uint32((uint64(unix%int64(time.Second)) << 32) / uint64(time.Second))
+	return sec, frac
+}
+
+// ParseGenericNACK scans an RTCP compound packet for a Generic NACK
+// (RFC 4585 PT=205, FMT=1) and returns every sequence number it asks to
+// have retransmitted: each PID plus every set bit in its following BLP
+// bitmask.
+func ParseGenericNACK(pkt []byte) (mediaSSRC uint32, seqs []uint16, ok bool) {
+	for len(pkt) >= 4 {
+		fmtAndVersion := pkt[0]
+		pt := pkt[1]
+		length := int(binary.BigEndian.Uint16(pkt[2:4]))
+		size := (length + 1) * 4
+		if size > len(pkt) {
+			break
+		}
+		body := pkt[4:size]
+
+		if pt == ptRTPFB && fmtAndVersion&0x1F == fmtGenericNACK && len(body) >= 8 {
+			mediaSSRC = binary.BigEndian.Uint32(body[4:8])
+			for i := 8; i+4 <= len(body); i += 4 {
+				pid := binary.BigEndian.Uint16(body[i : i+2])
+				blp := binary.BigEndian.Uint16(body[i+2 : i+4])
+
+				seqs = append(seqs, pid)
+				for b := uint(0); b < 16; b++ {
+					if blp&(1<<b) != 0 {
+						seqs = append(seqs, pid+uint16(b)+1)
+					}
+				}
+			}
+			ok = true
+		}
+
+		pkt = pkt[size:]
+	}
+	return mediaSSRC, seqs, ok
+}
+
+// ReceptionStats is a single RTCP receiver report block's worth of data
+// (RFC 3550 section 6.4.1).
+type ReceptionStats struct {
+	SourceSSRC   uint32
+	PacketsLost  uint32 // cumulative, 24 bits
+	FractionLost uint8
+	HighestSeq   uint32 // extended highest sequence number received
+	Jitter       uint32
+}
+
+// BuildReceiverReport encodes an RR packet with a single report block and
+// no sender info (we're reporting on what we received, not what we sent).
+func BuildReceiverReport(reporterSSRC uint32, stats ReceptionStats) []byte {
+	pkt := make([]byte, 8+24)
+	pkt[0] = 0x80 | 1 // V=2, P=0, RC=1
+	pkt[1] = ptReceiverReport
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(len(pkt)/4-1))
+	binary.BigEndian.PutUint32(pkt[4:8], reporterSSRC)
+
+	block := pkt[8:]
+	binary.BigEndian.PutUint32(block[0:4], stats.SourceSSRC)
+	block[4] = stats.FractionLost
+	block[5] = byte(stats.PacketsLost >> 16)
+	block[6] = byte(stats.PacketsLost >> 8)
+	block[7] = byte(stats.PacketsLost)
+	binary.BigEndian.PutUint32(block[8:12], stats.HighestSeq)
+	binary.BigEndian.PutUint32(block[12:16], stats.Jitter)
+	// last-SR and delay-since-last-SR (block[16:24]) stay zero: we don't
+	// track the timing of SRs we've received.
+	return pkt
+}
+
+// BuildSenderReport encodes an SR packet with no report blocks, describing
+// how much the sender has sent so far.
+func BuildSenderReport(ssrc uint32, sentAt time.Time, rtpTimestamp, packetCount, octetCount uint32) []byte {
+	ntpSec, ntpFrac := NTPTimestamp(sentAt)
+
+	pkt := make([]byte, 28)
+	pkt[0] = 0x80 // V=2, P=0, RC=0
+	pkt[1] = ptSenderReport
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(len(pkt)/4-1))
+	binary.BigEndian.PutUint32(pkt[4:8], ssrc)
+	binary.BigEndian.PutUint32(pkt[8:12], ntpSec)
+	binary.BigEndian.PutUint32(pkt[12:16], ntpFrac)
+	binary.BigEndian.PutUint32(pkt[16:20], rtpTimestamp)
+	binary.BigEndian.PutUint32(pkt[20:24], packetCount)
+	binary.BigEndian.PutUint32(pkt[24:28], octetCount)
+	return pkt
+}
@@ -0,0 +1,60 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// buildGenericNACK encodes an RTCP Generic NACK packet (RFC 4585 section
+// 6.2.1) with a single FCI entry, for feeding into ParseGenericNACK.
+func buildGenericNACK(mediaSSRC uint32, pid, blp uint16) []byte {
+	pkt := make([]byte, 16)
+	pkt[0] = 0x80 | fmtGenericNACK
+	pkt[1] = ptRTPFB
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(len(pkt)/4-1))
+	binary.BigEndian.PutUint32(pkt[4:8], 0xAAAAAAAA) // sender SSRC, unused by the parser
+	binary.BigEndian.PutUint32(pkt[8:12], mediaSSRC)
+	binary.BigEndian.PutUint16(pkt[12:14], pid)
+	binary.BigEndian.PutUint16(pkt[14:16], blp)
+	return pkt
+}
+
+func TestParseGenericNACK(t *testing.T) {
+	tests := []struct {
+		name     string
+		pid, blp uint16
+		wantSeqs []uint16
+	}{
+		{name: "pid only, no bits set", pid: 100, blp: 0, wantSeqs: []uint16{100}},
+		{name: "single bit set", pid: 100, blp: 0x0001, wantSeqs: []uint16{100, 101}},
+		{name: "several bits set", pid: 100, blp: 0x0005, wantSeqs: []uint16{100, 101, 103}},
+		{name: "high bit set", pid: 100, blp: 0x8000, wantSeqs: []uint16{100, 116}},
+		{name: "pid near wraparound", pid: 0xFFFF, blp: 0x0001, wantSeqs: []uint16{0xFFFF, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkt := buildGenericNACK(0x12345678, tt.pid, tt.blp)
+
+			ssrc, seqs, ok := ParseGenericNACK(pkt)
+			if !ok {
+				t.Fatalf("ParseGenericNACK returned ok=false")
+			}
+			if ssrc != 0x12345678 {
+				t.Fatalf("mediaSSRC = %#x, want %#x", ssrc, 0x12345678)
+			}
+			if !reflect.DeepEqual(seqs, tt.wantSeqs) {
+				t.Fatalf("seqs = %v, want %v", seqs, tt.wantSeqs)
+			}
+		})
+	}
+}
+
+func TestParseGenericNACKIgnoresOtherPackets(t *testing.T) {
+	sr := BuildSenderReport(1, time.Now(), 0, 0, 0)
+	if _, _, ok := ParseGenericNACK(sr); ok {
+		t.Fatalf("ParseGenericNACK matched a non-NACK packet")
+	}
+}
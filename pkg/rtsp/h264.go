@@ -0,0 +1,70 @@
+package rtsp
+
+import "encoding/binary"
+
+// rtpMTU is the largest RTP payload we'll put on the wire before falling
+// back to FU-A fragmentation (RFC 6184 section 5.8).
+const rtpMTU = 1400
+
+// packetizeNAL turns a single H.264 NAL unit into one or more RTP packets
+// (RFC 6184): a single packet if it fits in rtpMTU, FU-A fragments
+// otherwise. seq is advanced across every packet produced.
+func packetizeNAL(nal []byte, seq *uint16, ssrc uint32, ts uint32) [][]byte {
+	if len(nal) == 0 {
+		return nil
+	}
+
+	if len(nal) <= rtpMTU {
+		return [][]byte{rtpPacket(nal, seq, ssrc, ts, true)}
+	}
+
+	nalHeader := nal[0]
+	nalType := nalHeader & 0x1F
+	nri := nalHeader & 0x60
+	payload := nal[1:]
+
+	var pkts [][]byte
+	for first := true; len(payload) > 0; first = false {
+		chunkSize := rtpMTU - 2 // FU indicator + FU header
+		if chunkSize > len(payload) {
+			chunkSize = len(payload)
+		}
+		chunk := payload[:chunkSize]
+		payload = payload[chunkSize:]
+		last := len(payload) == 0
+
+		fuIndicator := 0x1C | nri // type 28 == FU-A
+		fuHeader := nalType
+		if first {
+			fuHeader |= 0x80
+		}
+		if last {
+			fuHeader |= 0x40
+		}
+
+		fragment := make([]byte, 2+len(chunk))
+		fragment[0] = fuIndicator
+		fragment[1] = fuHeader
+		copy(fragment[2:], chunk)
+
+		pkts = append(pkts, rtpPacket(fragment, seq, ssrc, ts, last))
+	}
+	return pkts
+}
+
+// rtpPacket builds a 12-byte RTP header followed by payload and advances seq.
+func rtpPacket(payload []byte, seq *uint16, ssrc uint32, ts uint32, marker bool) []byte {
+	pkt := make([]byte, 12+len(payload))
+	pkt[0] = 0x80 // V=2, P=0, X=0, CC=0
+	pkt[1] = 96   // PT=96 (dynamic, H264)
+	if marker {
+		pkt[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(pkt[2:], *seq)
+	binary.BigEndian.PutUint32(pkt[4:], ts)
+	binary.BigEndian.PutUint32(pkt[8:], ssrc)
+	copy(pkt[12:], payload)
+
+	*seq++
+	return pkt
+}
@@ -0,0 +1,43 @@
+package rtsp
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/HOTSONHONET/cam-feed/internal/hub"
+)
+
+// Serve starts the RTSP TCP listener on addr and wires it to router so
+// PLAY can stream real frames published by hub.HandleIngest. It blocks
+// until the listener fails or ctx is cancelled.
+func Serve(ctx context.Context, addr string, router *hub.StreamRouter) error {
+	streamRouter = router
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	log.Printf("[INFO] RTSP server listening on %s", addr)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				log.Println("[ERROR] accept: ", err)
+				continue
+			}
+		}
+
+		go HandleConn(conn)
+	}
+}
@@ -2,7 +2,9 @@ package rtsp
 
 import (
 	"bufio"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
 	"net"
 )
@@ -13,6 +15,21 @@ func HandleConn(conn net.Conn) {
 	reader := bufio.NewReader(conn)
 
 	for {
+		// A TCP session carries both RTSP requests and, once PLAY starts
+		// interleaved streaming, $-framed RTP/RTCP on the same connection.
+		// Peek the first byte to tell them apart before parsing a request.
+		if magic, err := reader.Peek(1); err == nil && magic[0] == 0x24 {
+			channel, payload, err := readInterleavedFrame(reader)
+			if err != nil {
+				log.Printf("[ERROR] error reading interleaved frame: %v", err)
+				return
+			}
+			if sess := sessionForConn(conn); sess != nil && channel == sess.transport.Interleaved[1] {
+				sess.handleIncomingRTCP(payload)
+			}
+			continue
+		}
+
 		// Parsing incoming RTSP request
 		req, err := readRequest(reader)
 		if err != nil {
@@ -33,7 +50,8 @@ func HandleConn(conn net.Conn) {
 
 		case "DESCRIBE":
 			// Return session description (SDP)
-			sdp := generateSDP(req.URI)
+			room, device := parseStreamURI(req.URI)
+			sdp := generateSDP(room, device)
 			respHeaders["Content-Base"] = req.URI + "/"
 			respHeaders["Content-Type"] = "application/sdp"
 			respHeaders["Content-Length"] = fmt.Sprint(len(sdp))
@@ -43,7 +61,7 @@ func HandleConn(conn net.Conn) {
 			// Allocate transport (UDP or TCP)
 			sessionID := setupSession(req, conn)
 			respHeaders["Session"] = sessionID
-			respHeaders["Transport"] = transportResponse(sessions[sessionID])
+			respHeaders["Transport"] = transportResponse(sessionByID(sessionID))
 			sendResponse(conn, 200, respHeaders, nil)
 
 		case "PLAY":
@@ -62,3 +80,29 @@ func HandleConn(conn net.Conn) {
 		}
 	}
 }
+
+// readInterleavedFrame reads one $-framed RTP/RTCP packet (RFC 2326
+// section 10.12): a 0x24 magic byte, a channel number, a 2-byte length,
+// then the payload.
+func readInterleavedFrame(reader *bufio.Reader) (channel byte, payload []byte, err error) {
+	if _, err := reader.Discard(1); err != nil {
+		return 0, nil, err
+	}
+
+	channel, err = reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(reader, lenBuf[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload = make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return channel, payload, nil
+}
@@ -1,40 +1,68 @@
 package rtsp
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/HOTSONHONET/cam-feed/internal/hub"
+	"github.com/HOTSONHONET/cam-feed/pkg/h264"
+	"github.com/HOTSONHONET/cam-feed/pkg/rtp"
+	"github.com/HOTSONHONET/cam-feed/pkg/rtp/packetcache"
 )
 
+// streamRouter is the shared bus set up by Serve; startRTPStreaming
+// subscribes to it to get real frames instead of emitting empty packets.
+var streamRouter *hub.StreamRouter
+
+// rtcpReportInterval is how often a session sends an RTCP sender report.
+const rtcpReportInterval = 5 * time.Second
+
 // This will hold parsed Transport header fields
 type transportParams struct {
-	UDP         bool
-	ClientPorts []int
-	ClientAddr  *net.UDPAddr
-	Interleaved [2]byte
+	UDP            bool
+	ClientPorts    []int
+	ClientAddr     *net.UDPAddr // client's RTP port
+	ClientRTCPAddr *net.UDPAddr // client's RTCP port
+	Interleaved    [2]byte
 }
 
 type session struct {
-	URI       string
+	URI      string
+	Room     string
+	DeviceID string
+
 	transport transportParams
 	udpRTP    *net.UDPConn
 	udpRTCP   *net.UDPConn
 	tcpConn   net.Conn
+
+	unsub func() // unsubscribes from streamRouter, set by startRTPStreaming
+	stop  chan struct{}
+
+	ssrc        uint32
+	cache       *packetcache.Cache
+	packetCount atomic.Uint32
+	octetCount  atomic.Uint32
 }
 
 var (
-	sessions   = make(map[string]*session)
-	sessionsMu sync.Mutex
+	sessions       = make(map[string]*session)
+	sessionsByConn = make(map[net.Conn]*session)
+	sessionsMu     sync.Mutex
 )
 
 // function to allocates transport and registers a new session
 func setupSession(req *RTSPRequest, conn net.Conn) string {
 	tp := parseTransport(req.Headers["Transport"])
 	sid := fmt.Sprintf("%08x", rand.Int31())
+	room, device := parseStreamURI(req.URI)
 
-	sess := &session{URI: req.URI, transport: tp}
+	sess := &session{URI: req.URI, Room: room, DeviceID: device, transport: tp, stop: make(chan struct{})}
 
 	if tp.UDP {
 		rtpConn, rtcpConn, err := openUDPSockets()
@@ -42,12 +70,24 @@ func setupSession(req *RTSPRequest, conn net.Conn) string {
 			fmt.Println("[ERROR] Error while open UDP sockets: ", err)
 		}
 		sess.udpRTP, sess.udpRTCP = rtpConn, rtcpConn
+
+		// the client only tells us its RTP/RTCP ports; the address is
+		// whatever socket it SETUP'd over
+		if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+			sess.transport.ClientAddr = &net.UDPAddr{IP: net.ParseIP(host), Port: tp.ClientPorts[0]}
+			if len(tp.ClientPorts) > 1 {
+				sess.transport.ClientRTCPAddr = &net.UDPAddr{IP: net.ParseIP(host), Port: tp.ClientPorts[1]}
+			}
+		}
 	} else {
 		sess.tcpConn = conn
 	}
 
 	sessionsMu.Lock()
 	sessions[sid] = sess
+	if !tp.UDP {
+		sessionsByConn[conn] = sess
+	}
 	sessionsMu.Unlock()
 	return sid
 }
@@ -58,31 +98,157 @@ func teardownSession(sid string) {
 	defer sessionsMu.Unlock()
 
 	if s, ok := sessions[sid]; ok {
+		if s.unsub != nil {
+			s.unsub()
+		}
+		close(s.stop)
 		if s.udpRTP != nil {
 			s.udpRTP.Close()
 			s.udpRTCP.Close()
 		}
+		if s.tcpConn != nil {
+			delete(sessionsByConn, s.tcpConn)
+		}
 
 		delete(sessions, sid)
 	}
 }
 
-// startRTPStreaming begins sending RTP packets for the session
+// sessionForConn looks up the session a TCP connection carries interleaved
+// RTP/RTCP for, so incoming $-framed RTCP can be routed to it.
+func sessionForConn(conn net.Conn) *session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return sessionsByConn[conn]
+}
+
+// sessionByID looks up a session by its SETUP-issued session ID.
+func sessionByID(sid string) *session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return sessions[sid]
+}
+
+// startRTPStreaming subscribes the session to streamRouter and fragments
+// every frame it receives into RTP packets for the negotiated transport.
 func startRTPStreaming(sid string) {
 	sessionsMu.Lock()
 	sess := sessions[sid]
 	sessionsMu.Unlock()
 
+	if sess == nil || streamRouter == nil {
+		return
+	}
+
+	frames, unsub := streamRouter.Subscribe(sess.Room, sess.DeviceID)
+
+	sessionsMu.Lock()
+	sess.unsub = unsub
+	sess.ssrc = rand.Uint32()
+	sess.cache = packetcache.New(packetcache.DefaultSize)
+	sessionsMu.Unlock()
+
+	ssrc := sess.ssrc
+	start := time.Now()
+	var seq uint16
+	var lastTS atomic.Uint32
+
 	go func() {
-		ticker := time.NewTicker(33 * time.Millisecond)
-		for range ticker.C {
-			packet := buildRTPPacket()
+		for {
+			select {
+			case <-sess.stop:
+				return
+			case frame, ok := <-frames:
+				if !ok {
+					return
+				}
+
+				ts := uint32(time.Since(start).Seconds() * 90000)
+				lastTS.Store(ts)
+
+				for _, nal := range h264.SplitNALUnits(frame) {
+					for _, pkt := range packetizeNAL(nal, &seq, ssrc, ts) {
+						sentSeq := binary.BigEndian.Uint16(pkt[2:4])
+						sess.cache.Store(sentSeq, pkt)
+						sess.packetCount.Add(1)
+						sess.octetCount.Add(uint32(len(pkt) - 12))
+
+						if sess.transport.UDP {
+							if sess.transport.ClientAddr == nil {
+								continue
+							}
+							sess.udpRTP.WriteTo(pkt, sess.transport.ClientAddr)
+						} else {
+							sendInterleaved(sess.tcpConn, pkt, sess.transport.Interleaved[0])
+						}
+					}
+				}
+			}
+		}
+	}()
 
+	go sess.rtcpSenderReportLoop(&lastTS)
+	if sess.transport.UDP {
+		go sess.rtcpUDPReader()
+	}
+}
+
+// rtcpSenderReportLoop periodically emits an RTCP SR describing how much
+// this session has sent, per RFC 3550 section 6.4.1.
+func (sess *session) rtcpSenderReportLoop(lastTS *atomic.Uint32) {
+	ticker := time.NewTicker(rtcpReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sess.stop:
+			return
+		case <-ticker.C:
+			sr := rtp.BuildSenderReport(sess.ssrc, time.Now(), lastTS.Load(), sess.packetCount.Load(), sess.octetCount.Load())
 			if sess.transport.UDP {
-				sess.udpRTP.WriteTo(packet, sess.transport.ClientAddr)
+				if sess.transport.ClientRTCPAddr != nil {
+					sess.udpRTCP.WriteTo(sr, sess.transport.ClientRTCPAddr)
+				}
 			} else {
-				sendInterleaved(sess.tcpConn, packet, 0)
+				sendInterleaved(sess.tcpConn, sr, sess.transport.Interleaved[1])
 			}
 		}
-	}()
+	}
+}
+
+// rtcpUDPReader reads RTCP packets from the client (Generic NACKs and
+// receiver reports) off the session's RTCP socket and retransmits any
+// NACKed packet still in the cache.
+func (sess *session) rtcpUDPReader() {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := sess.udpRTCP.ReadFrom(buf)
+		if err != nil {
+			return // socket closed by teardownSession
+		}
+		sess.handleIncomingRTCP(buf[:n])
+	}
+}
+
+// handleIncomingRTCP resends any cached packet a Generic NACK (RFC 4585)
+// requests retransmission of.
+func (sess *session) handleIncomingRTCP(data []byte) {
+	_, seqs, ok := rtp.ParseGenericNACK(data)
+	if !ok {
+		return
+	}
+
+	for _, seq := range seqs {
+		pkt, found := sess.cache.Get(seq)
+		if !found {
+			continue
+		}
+		if sess.transport.UDP {
+			if sess.transport.ClientAddr != nil {
+				sess.udpRTP.WriteTo(pkt, sess.transport.ClientAddr)
+			}
+		} else {
+			sendInterleaved(sess.tcpConn, pkt, sess.transport.Interleaved[0])
+		}
+	}
 }
@@ -1,13 +1,12 @@
 package rtsp
 
 import (
-	"encoding/binary"
+	"encoding/base64"
 	"fmt"
-	"math/rand"
 	"net"
+	"net/url"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // func to parse transport header into transportParams
@@ -55,24 +54,48 @@ func openUDPSockets() (*net.UDPConn, *net.UDPConn, error) {
 	return rtpConn, rtcpConn, nil
 }
 
-// function to generate a minimal SDP description for the stream
-func generateSDP(uri string) string {
-	return fmt.Sprintf(`v=0
-		" +
-        "o=- 0 0 IN IP4 0.0.0.0
-" +
-        "s=Go RTSP Server
-" +
-        "c=IN IP4 0.0.0.0
-" +
-        "t=0 0
-" +
-        "m=video 0 RTP/AVP 96
-" +
-        "a=rtpmap:96 H264/90000
-" +
-        "a=control:trackID=0
-	`)
+// parseStreamURI extracts room/deviceID from an
+// rtsp://host:8554/<room>/<device> URL, tolerating the trailing
+// "/trackID=N" control-URL suffix SETUP requests may send.
+func parseStreamURI(uri string) (room, device string) {
+	path := uri
+	if u, err := url.Parse(uri); err == nil {
+		path = u.Path
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 1 {
+		room = parts[0]
+	}
+	if len(parts) >= 2 && !strings.HasPrefix(parts[1], "trackID=") {
+		device = parts[1]
+	}
+	return room, device
+}
+
+// function to generate an SDP description for the stream, built from the
+// codec metadata the device handshook with hub.HandleIngest.
+func generateSDP(room, device string) string {
+	fmtp := ""
+	if streamRouter != nil {
+		if meta, ok := streamRouter.Meta(room, device); ok && len(meta.SPS) > 0 && len(meta.PPS) > 0 {
+			fmtp = fmt.Sprintf(
+				"a=fmtp:96 packetization-mode=1;sprop-parameter-sets=%s,%s\r\n",
+				base64.StdEncoding.EncodeToString(meta.SPS),
+				base64.StdEncoding.EncodeToString(meta.PPS),
+			)
+		}
+	}
+
+	return "v=0\r\n" +
+		"o=- 0 0 IN IP4 0.0.0.0\r\n" +
+		"s=cam-feed\r\n" +
+		"c=IN IP4 0.0.0.0\r\n" +
+		"t=0 0\r\n" +
+		"m=video 0 RTP/AVP 96\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		fmtp +
+		"a=control:trackID=0\r\n"
 }
 
 // func to create Transport response header for SETUP
@@ -92,22 +115,6 @@ func transportResponse(sess *session) string {
 	return fmt.Sprintf("RTP/AVP/TCP;interleaved=%d-%d", tp.Interleaved[0], tp.Interleaved[1])
 }
 
-// func to build RTP packets (header + no payload)
-func buildRTPPacket() []byte {
-	// RTP header: Version=2, P=0, X=0, CC=0, M=0, PT=96
-	seq := uint16(rand.Uint32())
-	ts := uint32(time.Now().UnixNano() / 1e6 * 90) // 90kHz clock
-	ssrc := rand.Uint32()
-	packet := make([]byte, 12)
-	packet[0] = 0x80
-	packet[1] = 96
-	binary.BigEndian.PutUint16(packet[2:], seq)
-	binary.BigEndian.PutUint32(packet[4:], ts)
-	binary.BigEndian.PutUint32(packet[8:], ssrc)
-
-	return packet
-}
-
 // func to wrap and send an RTP packet over the RTSP TCP connection
 func sendInterleaved(conn net.Conn, packet []byte, channel byte) error {
 	header := []byte{0x24, channel, byte(len(packet) >> 8), byte(len(packet) & 0xFF)}